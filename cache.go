@@ -0,0 +1,186 @@
+package minio
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/kjk/common/atomicfile"
+	"github.com/minio/minio-go/v7"
+)
+
+// CachedClient wraps a Client with a local disk cache so repeated
+// DownloadFileAtomically calls for an unchanged object short-circuit to a
+// local copy instead of re-fetching from the remote. Cache entries are
+// keyed by bucket/remotePath plus the object's ETag, verified on every
+// call with a cheap StatObject, and evicted LRU-style once the cache
+// exceeds maxBytes.
+type CachedClient struct {
+	*Client
+	dir      string
+	maxBytes int64
+	mu       sync.Mutex
+	inUse    map[string]int
+}
+
+// WithCache wraps c with a local disk cache rooted at dir, evicting the
+// least recently used entries once the cache exceeds maxBytes.
+func (c *Client) WithCache(dir string, maxBytes int64) (*CachedClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &CachedClient{
+		Client:   c,
+		dir:      dir,
+		maxBytes: maxBytes,
+		inUse:    map[string]int{},
+	}, nil
+}
+
+// acquire marks path as being read so evictIfNeeded won't remove it out
+// from under an in-flight DownloadFileAtomically call.
+func (cc *CachedClient) acquire(path string) {
+	cc.mu.Lock()
+	cc.inUse[path]++
+	cc.mu.Unlock()
+}
+
+func (cc *CachedClient) release(path string) {
+	cc.mu.Lock()
+	cc.inUse[path]--
+	if cc.inUse[path] <= 0 {
+		delete(cc.inUse, path)
+	}
+	cc.mu.Unlock()
+}
+
+func (cc *CachedClient) isInUse(path string) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.inUse[path] > 0
+}
+
+// cachePath returns the on-disk path for bucket/remotePath at a given
+// ETag. Different ETags for the same remotePath get different cache
+// files, so a changed object doesn't clobber (or get served from) a stale
+// entry.
+func (cc *CachedClient) cachePath(remotePath string, etag string) string {
+	safeName := strings.ReplaceAll(strings.TrimPrefix(remotePath, "/"), "/", "_")
+	return filepath.Join(cc.dir, cc.bucket, fmt.Sprintf("%s.%s", safeName, etag))
+}
+
+// DownloadFileAtomically downloads remotePath to dstPath, serving from the
+// local disk cache when the remote ETag (checked via StatObject) matches
+// a cached copy.
+func (cc *CachedClient) DownloadFileAtomically(dstPath string, remotePath string) error {
+	info, err := cc.c.StatObject(ctx(), cc.bucket, remotePath, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+	etag := strings.Trim(info.ETag, `"`)
+	cachePath := cc.cachePath(remotePath, etag)
+
+	// Held for the rest of this call so evictIfNeeded (run by any
+	// concurrent download, including this one) never removes the file
+	// out from under the download/copy below.
+	cc.acquire(cachePath)
+	defer cc.release(cachePath)
+
+	_, statErr := os.Stat(cachePath)
+	if statErr != nil {
+		if err := cc.Client.DownloadFileAtomically(cachePath, remotePath); err != nil {
+			return err
+		}
+		if err := cc.evictIfNeeded(); err != nil {
+			return err
+		}
+	}
+
+	// Bump recency for the LRU eviction policy, whether this was a hit or
+	// a fresh download.
+	now := time.Now()
+	_ = os.Chtimes(cachePath, now, now)
+
+	return copyFileAtomically(cachePath, dstPath)
+}
+
+func copyFileAtomically(srcPath string, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	dst, err := atomicfile.New(dstPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.Close()
+}
+
+// evictIfNeeded removes the least recently used cache entries until the
+// cache is back under maxBytes. It's flock-guarded so concurrent
+// processes sharing the same cache directory don't race each other.
+func (cc *CachedClient) evictIfNeeded() error {
+	lock := flock.New(filepath.Join(cc.dir, ".lock"))
+	if err := lock.Lock(); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	var total int64
+	err := filepath.Walk(cc.dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Base(path) == ".lock" {
+			return nil
+		}
+		entries = append(entries, entry{path: path, size: fi.Size(), modTime: fi.ModTime()})
+		total += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if total <= cc.maxBytes {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= cc.maxBytes {
+			break
+		}
+		if cc.isInUse(e.path) {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil {
+			continue
+		}
+		total -= e.size
+	}
+	return nil
+}
@@ -2,20 +2,27 @@ package minio
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"mime"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/andybalholm/brotli"
 	"github.com/kjk/common/atomicfile"
 	"github.com/kjk/common/u"
+	"github.com/klauspost/compress/zstd"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
@@ -25,6 +32,18 @@ type Config struct {
 	Secret   string
 	Bucket   string
 	Endpoint string
+
+	// Secure selects TLS for the connection to Endpoint. The zero value
+	// connects over plain HTTP, so set it to true for TLS endpoints.
+	Secure bool
+	// Region is passed through to the minio client as-is; leave empty to
+	// let the server pick the default region.
+	Region string
+
+	// CredentialsProvider, when set, is used instead of Access/Secret to
+	// authenticate. Use NewWithEnv, NewWithIAM or NewWithChain to build one
+	// of the common credential chains instead of constructing it by hand.
+	CredentialsProvider *credentials.Credentials
 }
 
 type Client struct {
@@ -38,13 +57,21 @@ func New(config *Config) (*Client, error) {
 		return nil, errors.New("must provide config")
 	}
 	c := config
-	if c.Access == "" || c.Secret == "" || c.Bucket == "" || c.Endpoint == "" {
+	if c.Bucket == "" || c.Endpoint == "" {
 		return nil, errors.New("must provide all fields in config")
 	}
+	creds := c.CredentialsProvider
+	if creds == nil {
+		if c.Access == "" || c.Secret == "" {
+			return nil, errors.New("must provide all fields in config")
+		}
+		creds = credentials.NewStaticV4(c.Access, c.Secret, "")
+	}
 
 	mc, err := minio.New(c.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(c.Access, c.Secret, ""),
-		Secure: true,
+		Creds:  creds,
+		Secure: c.Secure,
+		Region: c.Region,
 	})
 	if err != nil {
 		return nil, err
@@ -64,6 +91,51 @@ func New(config *Config) (*Client, error) {
 	}, nil
 }
 
+// NewWithEnv builds a client whose credentials come from the standard AWS
+// and minio client environment variables (AWS_ACCESS_KEY_ID /
+// AWS_SECRET_ACCESS_KEY and MINIO_ACCESS_KEY / MINIO_SECRET_KEY).
+func NewWithEnv(config *Config) (*Client, error) {
+	config.CredentialsProvider = credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+	})
+	return New(config)
+}
+
+// NewWithIAM builds a client whose credentials come from the EC2/ECS
+// instance metadata service.
+func NewWithIAM(config *Config) (*Client, error) {
+	config.CredentialsProvider = credentials.NewIAM("")
+	return New(config)
+}
+
+// NewWithChain builds a client backed by an ordered credential chain:
+// user-supplied static creds (if Access/Secret are set), environment
+// variables, the AWS and minio client config files, and finally IAM
+// instance credentials. The first provider in the chain that has
+// credentials wins.
+func NewWithChain(config *Config) (*Client, error) {
+	var providers []credentials.Provider
+	if config.Access != "" && config.Secret != "" {
+		providers = append(providers, &credentials.Static{
+			Value: credentials.Value{
+				AccessKeyID:     config.Access,
+				SecretAccessKey: config.Secret,
+				SignerType:      credentials.SignatureV4,
+			},
+		})
+	}
+	providers = append(providers,
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+		&credentials.FileAWSCredentials{},
+		&credentials.FileMinioClient{},
+		&credentials.IAM{},
+	)
+	config.CredentialsProvider = credentials.NewChainCredentials(providers)
+	return New(config)
+}
+
 func (c *Client) URLBase() string {
 	url := c.c.EndpointURL()
 	return fmt.Sprintf("https://%s.%s/", c.bucket, url.Host)
@@ -146,21 +218,201 @@ func (c *Client) UploadDataPrivate(remotePath string, data []byte) error {
 	return c.uploadData(remotePath, data, false)
 }
 
-func (c *Client) UploadDir(dirRemote string, dirLocal string) error {
-	files, err := ioutil.ReadDir(dirLocal)
+// UploadTreeOptions controls the behavior of UploadTree.
+type UploadTreeOptions struct {
+	// Public marks uploaded objects as publicly readable, like
+	// UploadFilePublic.
+	Public bool
+	// Exclude is a list of glob patterns (as matched by path.Match)
+	// evaluated against each file's path relative to dirLocal, using
+	// forward slashes. A matching file is skipped entirely.
+	Exclude []string
+	// ContentTypeFunc, if set, overrides mime detection for a given local
+	// path. Return "" to fall back to the default detection.
+	ContentTypeFunc func(path string) string
+	// Concurrency is the number of files uploaded in parallel. Defaults to
+	// runtime.NumCPU() when <= 0.
+	Concurrency int
+	// Progress, if set, is called after each file is processed (uploaded,
+	// skipped or failed) with the cumulative bytes of files processed so
+	// far (not just bytes actually sent over the wire) and the total
+	// bytes of all files in the tree.
+	Progress func(done, total int64)
+	// DryRun computes what would be uploaded/skipped without calling
+	// PutObject, so BytesTransferred in the result reflects bytes that
+	// would have been sent.
+	DryRun bool
+}
+
+// UploadTreeResult summarizes the outcome of an UploadTree call.
+type UploadTreeResult struct {
+	Uploaded         int
+	Skipped          int
+	Failed           int
+	BytesTransferred int64
+}
+
+type uploadTreeFile struct {
+	pathLocal  string
+	pathRemote string
+	size       int64
+	modTime    time.Time
+}
+
+// UploadTree recursively uploads dirLocal to dirRemote, skipping files
+// whose content hasn't changed (by comparing a local MD5 against the
+// remote ETag via StatObject to avoid redundant PUTs) and fanning the
+// rest out across a worker pool.
+func (c *Client) UploadTree(ctx context.Context, dirRemote string, dirLocal string, opts UploadTreeOptions) (UploadTreeResult, error) {
+	var result UploadTreeResult
+
+	var files []uploadTreeFile
+	var totalBytes int64
+	err := filepath.WalkDir(dirLocal, func(pathLocal string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dirLocal, pathLocal)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		for _, pattern := range opts.Exclude {
+			if matched, _ := path.Match(pattern, relPath); matched {
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		pathRemote := path.Join(dirRemote, relPath)
+		files = append(files, uploadTreeFile{pathLocal: pathLocal, pathRemote: pathRemote, size: info.Size(), modTime: info.ModTime()})
+		totalBytes += info.Size()
+		return nil
+	})
 	if err != nil {
-		return err
+		return result, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	var mu sync.Mutex
+	var processedBytes int64
+	var firstErr error
+	jobs := make(chan uploadTreeFile)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				skipped, err := c.uploadTreeEntry(ctx, f, opts)
+				mu.Lock()
+				switch {
+				case err != nil:
+					result.Failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+				case skipped:
+					result.Skipped++
+				default:
+					result.Uploaded++
+					result.BytesTransferred += f.size
+				}
+				processedBytes += f.size
+				done, total := processedBytes, totalBytes
+				mu.Unlock()
+				if opts.Progress != nil {
+					opts.Progress(done, total)
+				}
+			}
+		}()
 	}
 	for _, f := range files {
-		fname := f.Name()
-		pathLocal := filepath.Join(dirLocal, fname)
-		pathRemote := path.Join(dirRemote, fname)
-		_, err := c.UploadFilePublic(pathRemote, pathLocal)
-		if err != nil {
-			return fmt.Errorf("upload of '%s' as '%s' failed with '%s'", pathLocal, pathRemote, err)
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d of %d files failed to upload, first error: %v", result.Failed, len(files), firstErr)
+	}
+	return result, nil
+}
+
+// uploadTreeEntry uploads a single file for UploadTree, returning
+// skipped=true when the remote copy is already up to date.
+func (c *Client) uploadTreeEntry(ctx context.Context, f uploadTreeFile, opts UploadTreeOptions) (skipped bool, err error) {
+	if info, statErr := c.c.StatObject(ctx, c.bucket, f.pathRemote, minio.StatObjectOptions{}); statErr == nil {
+		etag := strings.Trim(info.ETag, `"`)
+		if isMultipartETag(etag) {
+			// A multipart ETag is <hash-of-part-ETags>-<numParts>, which
+			// can never equal the plain MD5 of the file; fall back to
+			// size+mtime since we can't cheaply recompute it.
+			if info.Size == f.size && !f.modTime.After(info.LastModified) {
+				return true, nil
+			}
+		} else {
+			localMD5, err := fileMD5Hex(f.pathLocal)
+			if err != nil {
+				return false, err
+			}
+			if etag == localMD5 {
+				return true, nil
+			}
 		}
 	}
-	return nil
+
+	if opts.DryRun {
+		return false, nil
+	}
+
+	contentType := ""
+	if opts.ContentTypeFunc != nil {
+		contentType = opts.ContentTypeFunc(f.pathLocal)
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(f.pathLocal))
+	}
+	putOpts := minio.PutObjectOptions{
+		ContentType: contentType,
+	}
+	if opts.Public {
+		setPublicObjectMetadata(&putOpts)
+	}
+	_, err = c.c.FPutObject(ctx, c.bucket, f.pathRemote, f.pathLocal, putOpts)
+	if err != nil {
+		return false, fmt.Errorf("upload of '%s' as '%s' failed with '%s'", f.pathLocal, f.pathRemote, err)
+	}
+	return false, nil
+}
+
+func fileMD5Hex(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isMultipartETag reports whether etag is in the "<hash>-<numParts>" form
+// S3 uses for objects that were uploaded as multiple parts, as opposed to
+// a plain MD5 hex digest.
+func isMultipartETag(etag string) bool {
+	return strings.Contains(etag, "-")
 }
 
 func (c *Client) ListObjects(prefix string) <-chan minio.ObjectInfo {
@@ -177,43 +429,212 @@ func (c *Client) Remove(remotePath string) error {
 	return err
 }
 
-func brotliCompress(path string) ([]byte, error) {
-	var buf bytes.Buffer
-	f, err := os.Open(path)
+// PresignedGetURL returns a time-limited URL that lets anyone download
+// remotePath without needing credentials, even when the object was
+// uploaded with UploadFilePrivate. reqParams can be used to set response
+// headers such as "response-content-disposition"; pass nil if not needed.
+func (c *Client) PresignedGetURL(remotePath string, expiry time.Duration, reqParams url.Values) (string, error) {
+	u, err := c.c.PresignedGetObject(ctx(), c.bucket, remotePath, expiry, reqParams)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	w := brotli.NewWriterLevel(&buf, brotli.BestCompression)
-	_, err = io.Copy(w, f)
+	return u.String(), nil
+}
+
+// PresignedPutURL returns a time-limited URL that lets anyone upload
+// remotePath directly, without proxying the bytes through our server.
+func (c *Client) PresignedPutURL(remotePath string, expiry time.Duration) (string, error) {
+	u, err := c.c.PresignedPutObject(ctx(), c.bucket, remotePath, expiry)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	err = w.Close()
-	if err != nil {
-		return nil, err
+	return u.String(), nil
+}
+
+// PresignedPostPolicy returns a URL and form fields for a browser to POST
+// remotePath's bytes directly to the bucket, constrained to at most
+// maxSize bytes and a Content-Type starting with contentTypePrefix.
+func (c *Client) PresignedPostPolicy(remotePath string, expiry time.Duration, maxSize int64, contentTypePrefix string) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(c.bucket); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetKey(remotePath); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expiry)); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentLengthRange(0, maxSize); err != nil {
+		return "", nil, err
 	}
-	err = f.Close()
+	if contentTypePrefix != "" {
+		if err := policy.SetContentTypeStartsWith(contentTypePrefix); err != nil {
+			return "", nil, err
+		}
+	}
+	u, formData, err := c.c.PresignedPostPolicy(ctx(), policy)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
-	return buf.Bytes(), nil
+	return u.String(), formData, nil
 }
 
-func (c *Client) UploadFileBrotliCompressedPublic(remotePath string, path string) (info minio.UploadInfo, err error) {
-	// TODO: use io.Pipe() to do compression more efficiently
-	d, err := brotliCompress(path)
+// CompressionEncoding identifies a streaming compression format used when
+// uploading and downloading objects. It maps directly to the standard
+// Content-Encoding header value.
+type CompressionEncoding string
+
+const (
+	CompressionBrotli CompressionEncoding = "br"
+	CompressionGzip   CompressionEncoding = "gzip"
+	CompressionZstd   CompressionEncoding = "zstd"
+)
+
+// compressedUploadPartSize is the part size used with PutObject when the
+// object size isn't known upfront (streaming compression). minio-go
+// requires either a known size or an explicit part size when the size is
+// -1.
+const compressedUploadPartSize = 16 * 1024 * 1024
+
+// newCompressWriter wraps w with a streaming compressor for enc, writing
+// compressed bytes to w as they're produced.
+func newCompressWriter(enc CompressionEncoding, w io.Writer) (io.WriteCloser, error) {
+	switch enc {
+	case CompressionBrotli:
+		return brotli.NewWriterLevel(w, brotli.BestCompression), nil
+	case CompressionGzip:
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported compression encoding '%s'", enc)
+}
+
+// contentTypeForCompressed returns the content type derived from
+// remotePath's extension after stripping a trailing compressed-file
+// suffix (.br, .gz, .zst), so callers that upload e.g. "styles.css.br"
+// still get "text/css" instead of the generic brotli/gzip type.
+func contentTypeForCompressed(remotePath string) string {
+	base := remotePath
+	for _, suffix := range []string{".br", ".gz", ".zst"} {
+		if strings.HasSuffix(base, suffix) {
+			base = strings.TrimSuffix(base, suffix)
+			break
+		}
+	}
+	return mime.TypeByExtension(filepath.Ext(base))
+}
+
+// UploadFileCompressed streams path through a compressor for enc and
+// uploads the result to remotePath, setting Content-Encoding to enc and
+// Content-Type from the decompressed file extension. Compression runs
+// concurrently with the upload via an io.Pipe so the whole file never
+// needs to be buffered in memory. Since the compressed size isn't known
+// upfront, it uploads with objectSize=-1 and an explicit PartSize.
+func (c *Client) UploadFileCompressed(remotePath string, path string, enc CompressionEncoding, public bool) (info minio.UploadInfo, err error) {
+	f, err := os.Open(path)
 	if err != nil {
 		return
 	}
-	ext := filepath.Ext(remotePath)
-	contentType := mime.TypeByExtension(ext)
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	cw, err := newCompressWriter(enc, pw)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		if _, copyErr := io.Copy(cw, f); copyErr != nil {
+			cw.Close()
+			pw.CloseWithError(copyErr)
+			return
+		}
+		if closeErr := cw.Close(); closeErr != nil {
+			pw.CloseWithError(closeErr)
+			return
+		}
+		pw.Close()
+	}()
+
 	opts := minio.PutObjectOptions{
-		ContentType: contentType,
+		ContentType:     contentTypeForCompressed(remotePath),
+		ContentEncoding: string(enc),
+		PartSize:        compressedUploadPartSize,
+	}
+	if public {
+		setPublicObjectMetadata(&opts)
+	}
+	info, err = c.c.PutObject(ctx(), c.bucket, remotePath, pr, -1, opts)
+	if err != nil {
+		// Unblock the compressing goroutine, which may still be writing
+		// into pw: without this, an early PutObject failure (e.g. a
+		// network error) leaks it forever since nothing is draining pr.
+		pr.CloseWithError(err)
+		return info, err
+	}
+	pr.Close()
+	return info, nil
+}
+
+func (c *Client) UploadFileBrotliCompressedPublic(remotePath string, path string) (info minio.UploadInfo, err error) {
+	return c.UploadFileCompressed(remotePath, path, CompressionBrotli, true)
+}
+
+// DownloadFileAtomicallyDecoded downloads remotePath and, based on the
+// Content-Encoding the object was stored with, transparently decompresses
+// it while writing to dstPath atomically.
+func (c *Client) DownloadFileAtomicallyDecoded(dstPath string, remotePath string) error {
+	statInfo, err := c.c.StatObject(ctx(), c.bucket, remotePath, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	obj, err := c.c.GetObject(ctx(), c.bucket, remotePath, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	// ensure there's a dir for destination file
+	dir := filepath.Dir(dstPath)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
 	}
-	setPublicObjectMetadata(&opts)
-	r := bytes.NewReader(d)
-	fsize := int64(len(d))
-	return c.c.PutObject(ctx(), c.bucket, remotePath, r, fsize, opts)
+
+	f, err := atomicfile.New(dstPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = obj
+	switch CompressionEncoding(statInfo.Metadata.Get("Content-Encoding")) {
+	case CompressionBrotli:
+		r = brotli.NewReader(obj)
+	case CompressionGzip:
+		gzr, err := gzip.NewReader(obj)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+		r = gzr
+	case CompressionZstd:
+		zr, err := zstd.NewReader(obj)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 func ctx() context.Context {
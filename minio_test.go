@@ -0,0 +1,73 @@
+package minio
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	mc, err := minio.New("s3.example.com", &minio.Options{
+		Creds:  credentials.NewStaticV4("access", "secret", ""),
+		Secure: true,
+		Region: "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("minio.New: %v", err)
+	}
+	return &Client{c: mc, bucket: "test-bucket"}
+}
+
+func TestPresignedPostPolicyConditions(t *testing.T) {
+	c := newTestClient(t)
+
+	_, formData, err := c.PresignedPostPolicy("uploads/photo.jpg", time.Hour, 10<<20, "image/")
+	if err != nil {
+		t.Fatalf("PresignedPostPolicy: %v", err)
+	}
+
+	encoded, ok := formData["policy"]
+	if !ok {
+		t.Fatalf("form data missing policy field: %v", formData)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decode policy: %v", err)
+	}
+
+	var doc struct {
+		Conditions []interface{} `json:"conditions"`
+	}
+	if err := json.Unmarshal(decoded, &doc); err != nil {
+		t.Fatalf("unmarshal policy: %v", err)
+	}
+
+	var sawLengthRange, sawContentType bool
+	for _, cond := range doc.Conditions {
+		arr, ok := cond.([]interface{})
+		if !ok || len(arr) != 3 {
+			continue
+		}
+		switch arr[0] {
+		case "content-length-range":
+			if arr[1] == float64(0) && arr[2] == float64(10<<20) {
+				sawLengthRange = true
+			}
+		case "starts-with":
+			if arr[1] == "$Content-Type" && arr[2] == "image/" {
+				sawContentType = true
+			}
+		}
+	}
+	if !sawLengthRange {
+		t.Errorf("policy missing content-length-range [0, 10<<20] condition: %s", decoded)
+	}
+	if !sawContentType {
+		t.Errorf("policy missing starts-with Content-Type image/ condition: %s", decoded)
+	}
+}
@@ -0,0 +1,260 @@
+package minio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// defaultResumablePartSize is the part size UploadFileResumable uses when
+// ResumableOptions.PartSize is unset.
+const defaultResumablePartSize = 16 * 1024 * 1024
+
+// minResumablePartSize is S3's minimum multipart part size. Rejecting a
+// smaller PartSize up front avoids uploading every part only to have
+// CompleteMultipartUpload fail with EntityTooSmall at the very end.
+const minResumablePartSize = 5 * 1024 * 1024
+
+// ResumableOptions controls UploadFileResumable.
+type ResumableOptions struct {
+	// PartSize is the size of each multipart upload part. Defaults to
+	// 16 MiB. Only used when starting a new upload; a resumed upload keeps
+	// the part size recorded in the sidecar file.
+	PartSize int64
+	// Concurrency is the number of parts uploaded in parallel. Defaults to
+	// runtime.NumCPU() when <= 0.
+	Concurrency int
+	// MaxRetries is the number of additional attempts per part after a
+	// failure, with exponential backoff between attempts. Defaults to 3.
+	MaxRetries int
+	// Public marks the completed object as publicly readable.
+	Public bool
+}
+
+// resumableSidecar is persisted next to the local file being uploaded so a
+// later call can resume an in-progress multipart upload.
+type resumableSidecar struct {
+	UploadID string `json:"uploadID"`
+	PartSize int64  `json:"partSize"`
+}
+
+func resumableSidecarPath(localPath string) string {
+	return localPath + ".miniopart"
+}
+
+func readResumableSidecar(path string) (*resumableSidecar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sc resumableSidecar
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+func writeResumableSidecar(path string, sc *resumableSidecar) error {
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// UploadFileResumable uploads localPath to remotePath as an explicit S3
+// multipart upload, persisting the upload ID to a sidecar file
+// (localPath + ".miniopart") so a later call with the same arguments can
+// resume instead of starting over. On resume it calls ListObjectParts to
+// find out which parts the server already has and only uploads the rest.
+func (c *Client) UploadFileResumable(remotePath string, localPath string, opts ResumableOptions) (info minio.UploadInfo, err error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return info, err
+	}
+	size := stat.Size()
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	maxRetries := opts.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+
+	core := minio.Core{Client: c.c}
+	sidecarPath := resumableSidecarPath(localPath)
+
+	sc, err := readResumableSidecar(sidecarPath)
+	if err != nil {
+		return info, err
+	}
+
+	var uploadID string
+	var partSize int64
+	if sc != nil && sc.UploadID != "" {
+		uploadID = sc.UploadID
+		partSize = sc.PartSize
+	} else {
+		partSize = opts.PartSize
+		if partSize <= 0 {
+			partSize = defaultResumablePartSize
+		}
+		if partSize < minResumablePartSize && size > partSize {
+			return info, fmt.Errorf("PartSize %d is below the %d minimum required for a multipart upload", partSize, minResumablePartSize)
+		}
+		putOpts := minio.PutObjectOptions{
+			ContentType: mime.TypeByExtension(filepath.Ext(remotePath)),
+		}
+		if opts.Public {
+			setPublicObjectMetadata(&putOpts)
+		}
+		uploadID, err = core.NewMultipartUpload(ctx(), c.bucket, remotePath, putOpts)
+		if err != nil {
+			return info, err
+		}
+		sc = &resumableSidecar{UploadID: uploadID, PartSize: partSize}
+		if err := writeResumableSidecar(sidecarPath, sc); err != nil {
+			return info, err
+		}
+	}
+
+	totalParts := int((size + partSize - 1) / partSize)
+	if totalParts == 0 {
+		totalParts = 1
+	}
+
+	completed := map[int]minio.CompletePart{}
+	partNumberMarker := 0
+	for {
+		res, err := core.ListObjectParts(ctx(), c.bucket, remotePath, uploadID, partNumberMarker, 1000)
+		if err != nil {
+			return info, err
+		}
+		for _, p := range res.ObjectParts {
+			completed[p.PartNumber] = minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag}
+		}
+		if !res.IsTruncated {
+			break
+		}
+		partNumberMarker = res.NextPartNumberMarker
+	}
+
+	var todo []int
+	for partNumber := 1; partNumber <= totalParts; partNumber++ {
+		if _, ok := completed[partNumber]; !ok {
+			todo = append(todo, partNumber)
+		}
+	}
+
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var uploadErr error
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range jobs {
+				offset := int64(partNumber-1) * partSize
+				partLen := partSize
+				if offset+partLen > size {
+					partLen = size - offset
+				}
+
+				var part minio.ObjectPart
+				var partErr error
+				for attempt := 0; attempt <= maxRetries; attempt++ {
+					if attempt > 0 {
+						time.Sleep(resumablePartBackoff(attempt))
+					}
+					sr := io.NewSectionReader(f, offset, partLen)
+					part, partErr = core.PutObjectPart(ctx(), c.bucket, remotePath, uploadID, partNumber, sr, partLen, minio.PutObjectPartOptions{})
+					if partErr == nil {
+						break
+					}
+				}
+
+				mu.Lock()
+				if partErr != nil {
+					if uploadErr == nil {
+						uploadErr = partErr
+					}
+				} else {
+					completed[partNumber] = minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, partNumber := range todo {
+		jobs <- partNumber
+	}
+	close(jobs)
+	wg.Wait()
+
+	if uploadErr != nil {
+		return info, uploadErr
+	}
+
+	parts := make([]minio.CompletePart, 0, totalParts)
+	for partNumber := 1; partNumber <= totalParts; partNumber++ {
+		part, ok := completed[partNumber]
+		if !ok {
+			return info, fmt.Errorf("missing part %d of '%s' after upload", partNumber, remotePath)
+		}
+		parts = append(parts, part)
+	}
+
+	info, err = core.CompleteMultipartUpload(ctx(), c.bucket, remotePath, uploadID, parts, minio.PutObjectOptions{})
+	if err != nil {
+		return info, err
+	}
+
+	_ = os.Remove(sidecarPath)
+	return info, nil
+}
+
+// AbortResumable aborts an in-progress UploadFileResumable upload for
+// remotePath/localPath, if any, and removes the sidecar file.
+func (c *Client) AbortResumable(remotePath string, localPath string) error {
+	sidecarPath := resumableSidecarPath(localPath)
+	sc, err := readResumableSidecar(sidecarPath)
+	if err != nil {
+		return err
+	}
+	if sc == nil || sc.UploadID == "" {
+		return nil
+	}
+	core := minio.Core{Client: c.c}
+	if err := core.AbortMultipartUpload(ctx(), c.bucket, remotePath, sc.UploadID); err != nil {
+		return err
+	}
+	return os.Remove(sidecarPath)
+}
+
+func resumablePartBackoff(attempt int) time.Duration {
+	d := time.Duration(1<<uint(attempt)) * 200 * time.Millisecond
+	if d > 5*time.Second {
+		d = 5 * time.Second
+	}
+	return d
+}